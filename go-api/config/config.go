@@ -3,6 +3,7 @@ package config
 import (
     "log"
     "os"
+    "strconv"
     "sync"
 
     "github.com/joho/godotenv"
@@ -10,11 +11,37 @@ import (
 
 // Config — структура для хранения конфигурации приложения
 type Config struct {
-    Env         string
-    Port        string
-    PostgresDSN string
-    RedisAddr   string
-    OpenAIKey   string
+    Env              string
+    Port             string
+    PostgresDSN      string
+    RedisAddr        string
+    OpenAIKey        string
+    TelegramBotToken string
+
+    // LLMProvider выбирает реализацию dialog.LLMBackend: "openai" (по умолчанию) или "anthropic"
+    LLMProvider  string
+    LLMModel     string
+    AnthropicKey string
+
+    // TelegramMode выбирает способ приёма сообщений: "webhook" (Bot API) или "mtproto" (TDLib)
+    TelegramMode string
+    TGAPIID      int
+    TGAPIHash    string
+    TGSessionDir string
+
+    // Параметры пула воркеров очереди задач
+    WorkerCount          int
+    MaxTaskAttempts      int
+    VisibilityTimeoutSec int
+
+    // Параметры OIDC для входа персонала в дашборд/админ-эндпоинты. Пустой OIDCIssuer
+    // означает, что OIDC не настроен и middleware.AuthMiddleware работает в статическом режиме.
+    OIDCIssuer        string
+    OIDCClientID      string
+    OIDCClientSecret  string
+    OIDCUsernameClaim string
+    OIDCGroupsClaim   string
+    OIDCAutoOnboard   bool
 }
 
 var (
@@ -34,11 +61,38 @@ func LoadConfig() *Config {
         }
 
         cfg = &Config{
-            Env:         getEnv("APP_ENV", "development"),
-            Port:        getEnv("PORT", "8080"),
-            PostgresDSN: mustHave("POSTGRES_DSN"),
-            RedisAddr:   getRedisAddr(),
-            OpenAIKey:   mustHave("OPENAI_API_KEY"),
+            Env:              getEnv("APP_ENV", "development"),
+            Port:             getEnv("PORT", "8080"),
+            PostgresDSN:      mustHave("POSTGRES_DSN"),
+            RedisAddr:        getRedisAddr(),
+            OpenAIKey:        mustHave("OPENAI_API_KEY"),
+            TelegramBotToken: mustHave("TELEGRAM_BOT_TOKEN"),
+            TelegramMode:     getEnv("TELEGRAM_MODE", "webhook"),
+            TGSessionDir:     getEnv("TG_SESSION_DIR", "./tdlib-session"),
+
+            LLMProvider:  getEnv("LLM_PROVIDER", "openai"),
+            LLMModel:     getEnv("LLM_MODEL", ""),
+            AnthropicKey: getEnv("ANTHROPIC_API_KEY", ""),
+
+            WorkerCount:          getEnvInt("WORKER_COUNT", 4),
+            MaxTaskAttempts:      getEnvInt("MAX_TASK_ATTEMPTS", 5),
+            VisibilityTimeoutSec: getEnvInt("VISIBILITY_TIMEOUT_SEC", 30),
+
+            OIDCIssuer:        getEnv("OIDC_ISSUER", ""),
+            OIDCClientID:      getEnv("OIDC_CLIENT_ID", ""),
+            OIDCClientSecret:  getEnv("OIDC_CLIENT_SECRET", ""),
+            OIDCUsernameClaim: getEnv("OIDC_USERNAME_CLAIM", "preferred_username"),
+            OIDCGroupsClaim:   getEnv("OIDC_GROUPS_CLAIM", "groups"),
+            OIDCAutoOnboard:   getEnvBool("OIDC_AUTO_ONBOARD", false),
+        }
+
+        if cfg.TelegramMode == "mtproto" {
+            cfg.TGAPIID = mustHaveInt("TG_API_ID")
+            cfg.TGAPIHash = mustHave("TG_API_HASH")
+        }
+
+        if cfg.LLMProvider == "anthropic" {
+            cfg.AnthropicKey = mustHave("ANTHROPIC_API_KEY")
         }
     })
     return cfg
@@ -61,6 +115,44 @@ func mustHave(key string) string {
     return ""
 }
 
+// getEnvInt — возвращает числовое значение переменной или дефолт, если она не задана или невалидна
+func getEnvInt(key string, defaultVal int) int {
+    val, ok := os.LookupEnv(key)
+    if !ok || val == "" {
+        return defaultVal
+    }
+    n, err := strconv.Atoi(val)
+    if err != nil {
+        log.Printf("⚠️ Переменная окружения %s должна быть числом, использую значение по умолчанию %d", key, defaultVal)
+        return defaultVal
+    }
+    return n
+}
+
+// getEnvBool — возвращает булево значение переменной или дефолт, если она не задана или невалидна
+func getEnvBool(key string, defaultVal bool) bool {
+    val, ok := os.LookupEnv(key)
+    if !ok || val == "" {
+        return defaultVal
+    }
+    b, err := strconv.ParseBool(val)
+    if err != nil {
+        log.Printf("⚠️ Переменная окружения %s должна быть булевой, использую значение по умолчанию %t", key, defaultVal)
+        return defaultVal
+    }
+    return b
+}
+
+// mustHaveInt — проверяет наличие обязательной переменной и разбирает её как число
+func mustHaveInt(key string) int {
+    val := mustHave(key)
+    n, err := strconv.Atoi(val)
+    if err != nil {
+        log.Fatalf("❌ Переменная окружения %s должна быть числом: %v", key, err)
+    }
+    return n
+}
+
 // getRedisAddr — извлекает адрес Redis из REDIS_URL или REDIS_ADDR
 func getRedisAddr() string {
     // Сначала пробуем REDIS_URL (как в .env)
@@ -5,26 +5,37 @@ import (
     "net/http"
     "runtime"
     "time"
+
+    "ai_seller/jobs"
+
+    "github.com/redis/go-redis/v9"
 )
 
 // DashboardStatus содержит краткую информацию о сервисе
 type DashboardStatus struct {
-    Uptime     string `json:"uptime"`
-    Goroutines int    `json:"goroutines"`
-    Status     string `json:"status"`
+    Uptime     string           `json:"uptime"`
+    Goroutines int              `json:"goroutines"`
+    Status     string           `json:"status"`
+    Queue      *jobs.QueueStats `json:"queue,omitempty"`
 }
 
 var startedAt = time.Now()
 
-// Handler возвращает текущий статус сервиса
-func Handler(w http.ResponseWriter, r *http.Request) {
-    status := DashboardStatus{
-        Uptime:     time.Since(startedAt).String(),
-        Goroutines: runtime.NumGoroutine(),
-        Status:     "ok",
-    }
+// NewHandler возвращает хендлер текущего статуса сервиса, дополненный
+// глубиной очереди задач и числом задач в обработке
+func NewHandler(rdb *redis.Client) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        status := DashboardStatus{
+            Uptime:     time.Since(startedAt).String(),
+            Goroutines: runtime.NumGoroutine(),
+            Status:     "ok",
+        }
 
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(status)
-}
+        if stats, err := jobs.Stats(r.Context(), rdb); err == nil {
+            status.Queue = &stats
+        }
 
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(status)
+    }
+}
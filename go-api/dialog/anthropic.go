@@ -0,0 +1,91 @@
+package dialog
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+)
+
+// AnthropicBackend — реализация LLMBackend поверх Anthropic Messages API
+type AnthropicBackend struct {
+    apiKey string
+    model  string
+}
+
+// NewAnthropicBackend создаёт backend на базе Anthropic с указанной моделью.
+// Пустая модель заменяется на claude-3-5-sonnet-latest.
+func NewAnthropicBackend(apiKey, model string) *AnthropicBackend {
+    if model == "" {
+        model = "claude-3-5-sonnet-latest"
+    }
+    return &AnthropicBackend{apiKey: apiKey, model: model}
+}
+
+type anthropicMessage struct {
+    Role    string `json:"role"`
+    Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+    Model     string             `json:"model"`
+    System    string             `json:"system,omitempty"`
+    Messages  []anthropicMessage `json:"messages"`
+    MaxTokens int                `json:"max_tokens"`
+}
+
+// Complete отправляет историю сообщений в Anthropic и возвращает текст ответа ассистента.
+// Messages API не принимает роль "system" внутри messages — она выносится в поле System.
+func (b *AnthropicBackend) Complete(ctx context.Context, messages []ChatMessage) (string, error) {
+    payload := anthropicRequest{Model: b.model, MaxTokens: 1024}
+    for _, m := range messages {
+        if m.Role == "system" {
+            if payload.System != "" {
+                payload.System += "\n\n"
+            }
+            payload.System += m.Content
+            continue
+        }
+        payload.Messages = append(payload.Messages, anthropicMessage{Role: m.Role, Content: m.Content})
+    }
+
+    body, err := json.Marshal(payload)
+    if err != nil {
+        return "", fmt.Errorf("сериализация запроса к Anthropic: %w", err)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.anthropic.com/v1/messages", bytes.NewReader(body))
+    if err != nil {
+        return "", err
+    }
+    req.Header.Set("x-api-key", b.apiKey)
+    req.Header.Set("anthropic-version", "2023-06-01")
+    req.Header.Set("Content-Type", "application/json")
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return "", fmt.Errorf("запрос к Anthropic: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        errBody, _ := io.ReadAll(resp.Body)
+        return "", fmt.Errorf("Anthropic вернул %d: %s", resp.StatusCode, string(errBody))
+    }
+
+    var result struct {
+        Content []struct {
+            Text string `json:"text"`
+        } `json:"content"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+        return "", fmt.Errorf("разбор ответа Anthropic: %w", err)
+    }
+    if len(result.Content) == 0 {
+        return "", fmt.Errorf("Anthropic не вернул ни одного блока содержимого")
+    }
+
+    return result.Content[0].Text, nil
+}
@@ -0,0 +1,20 @@
+package dialog
+
+import (
+    "fmt"
+
+    "ai_seller/config"
+)
+
+// NewLLMBackend выбирает реализацию LLMBackend по cfg.LLMProvider, позволяя подменить
+// OpenAI на Anthropic (или другой backend) без изменения кода DialogManager.
+func NewLLMBackend(cfg *config.Config) (LLMBackend, error) {
+    switch cfg.LLMProvider {
+    case "", "openai":
+        return NewOpenAIBackend(cfg.OpenAIKey, cfg.LLMModel), nil
+    case "anthropic":
+        return NewAnthropicBackend(cfg.AnthropicKey, cfg.LLMModel), nil
+    default:
+        return nil, fmt.Errorf("неизвестный LLM_PROVIDER: %s", cfg.LLMProvider)
+    }
+}
@@ -0,0 +1,203 @@
+package dialog
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/lib/pq"
+    "github.com/pkoukk/tiktoken-go"
+)
+
+// historyStore хранит историю сообщений пользователя в PostgreSQL и поддерживает
+// скользящее окно, ограниченное числом токенов
+type historyStore struct {
+    db        *sql.DB
+    maxTokens int
+    encoding  *tiktoken.Tiktoken
+}
+
+func newHistoryStore(db *sql.DB, maxTokens int) (*historyStore, error) {
+    encoding, err := tiktoken.GetEncoding("cl100k_base")
+    if err != nil {
+        return nil, fmt.Errorf("загрузка токенизатора: %w", err)
+    }
+
+    store := &historyStore{db: db, maxTokens: maxTokens, encoding: encoding}
+    if err := store.ensureSchema(); err != nil {
+        return nil, err
+    }
+    return store, nil
+}
+
+func (s *historyStore) ensureSchema() error {
+    _, err := s.db.Exec(`
+        CREATE TABLE IF NOT EXISTS conversations (
+            id         BIGSERIAL PRIMARY KEY,
+            user_id    TEXT NOT NULL,
+            role       TEXT NOT NULL,
+            content    TEXT NOT NULL,
+            tokens     INT NOT NULL,
+            created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+        )
+    `)
+    if err != nil {
+        return fmt.Errorf("создание таблицы conversations: %w", err)
+    }
+
+    _, err = s.db.Exec(`CREATE INDEX IF NOT EXISTS conversations_user_id_idx ON conversations (user_id, created_at)`)
+    if err != nil {
+        return fmt.Errorf("создание индекса conversations_user_id_idx: %w", err)
+    }
+    return nil
+}
+
+func (s *historyStore) countTokens(text string) int {
+    return len(s.encoding.Encode(text, nil, nil))
+}
+
+// append сохраняет сообщение в истории пользователя и сжимает старые сообщения,
+// если суммарный размер истории вышел за пределы окна
+func (s *historyStore) append(ctx context.Context, userID, role, content string, llm LLMBackend) error {
+    tokens := s.countTokens(content)
+    if _, err := s.db.ExecContext(ctx,
+        `INSERT INTO conversations (user_id, role, content, tokens) VALUES ($1, $2, $3, $4)`,
+        userID, role, content, tokens,
+    ); err != nil {
+        return fmt.Errorf("сохранение сообщения: %w", err)
+    }
+
+    return s.compact(ctx, userID, llm)
+}
+
+// window возвращает последние сообщения пользователя в хронологическом порядке,
+// чей суммарный размер не превышает maxTokens
+func (s *historyStore) window(ctx context.Context, userID string) ([]ChatMessage, error) {
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT role, content, tokens FROM conversations WHERE user_id = $1 ORDER BY created_at DESC`,
+        userID,
+    )
+    if err != nil {
+        return nil, fmt.Errorf("чтение истории диалога: %w", err)
+    }
+    defer rows.Close()
+
+    var recent []ChatMessage
+    var total int
+    for rows.Next() {
+        var role, content string
+        var tokens int
+        if err := rows.Scan(&role, &content, &tokens); err != nil {
+            return nil, err
+        }
+        if total+tokens > s.maxTokens {
+            break
+        }
+        total += tokens
+        recent = append(recent, ChatMessage{Role: role, Content: content})
+    }
+
+    for i, j := 0, len(recent)-1; i < j; i, j = i+1, j-1 {
+        recent[i], recent[j] = recent[j], recent[i]
+    }
+    return recent, nil
+}
+
+type historyRow struct {
+    id        int64
+    role      string
+    content   string
+    tokens    int
+    createdAt time.Time
+}
+
+// compact переносит сообщения, вышедшие за пределы окна maxTokens, в одну сжатую
+// системную запись через llm, чтобы не терять контекст старого разговора
+func (s *historyStore) compact(ctx context.Context, userID string, llm LLMBackend) error {
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT id, role, content, tokens, created_at FROM conversations WHERE user_id = $1 ORDER BY created_at DESC`,
+        userID,
+    )
+    if err != nil {
+        return fmt.Errorf("чтение истории для сжатия: %w", err)
+    }
+
+    var all []historyRow
+    var total int
+    for rows.Next() {
+        var r historyRow
+        if err := rows.Scan(&r.id, &r.role, &r.content, &r.tokens, &r.createdAt); err != nil {
+            rows.Close()
+            return err
+        }
+        all = append(all, r)
+        total += r.tokens
+    }
+    rows.Close()
+
+    if total <= s.maxTokens {
+        return nil
+    }
+
+    // all отсортирован от новых к старым — оставляем столько новых сообщений, сколько влезает в окно
+    var keep, kept int
+    for _, r := range all {
+        if kept+r.tokens > s.maxTokens {
+            break
+        }
+        kept += r.tokens
+        keep++
+    }
+
+    evicted := all[keep:]
+    if len(evicted) == 0 {
+        return nil
+    }
+
+    var transcript strings.Builder
+    ids := make([]int64, 0, len(evicted))
+    for i := len(evicted) - 1; i >= 0; i-- {
+        fmt.Fprintf(&transcript, "%s: %s\n", evicted[i].role, evicted[i].content)
+        ids = append(ids, evicted[i].id)
+    }
+
+    summary, err := llm.Complete(ctx, []ChatMessage{
+        {Role: "system", Content: "Сожми следующий фрагмент диалога в краткое резюме на русском, сохранив ключевые факты о клиенте и договорённости."},
+        {Role: "user", Content: transcript.String()},
+    })
+    if err != nil {
+        return fmt.Errorf("суммаризация истории: %w", err)
+    }
+
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+
+    if _, err := tx.ExecContext(ctx, `DELETE FROM conversations WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+        tx.Rollback()
+        return fmt.Errorf("удаление вытесненных сообщений: %w", err)
+    }
+
+    // window() отдаёт сообщения в хронологическом порядке, так что сводка должна встать
+    // РАНЬШЕ всех оставленных сообщений — иначе сжатие самых старых реплик окажется
+    // у LLM самым новым сообщением. Ставим created_at строго раньше старейшей из них.
+    summaryTime := time.Now()
+    if keep > 0 {
+        summaryTime = all[keep-1].createdAt.Add(-time.Millisecond)
+    } else if len(evicted) > 0 {
+        summaryTime = evicted[0].createdAt
+    }
+
+    if _, err := tx.ExecContext(ctx,
+        `INSERT INTO conversations (user_id, role, content, tokens, created_at) VALUES ($1, 'system', $2, $3, $4)`,
+        userID, summary, s.countTokens(summary), summaryTime,
+    ); err != nil {
+        tx.Rollback()
+        return fmt.Errorf("сохранение сводки: %w", err)
+    }
+
+    return tx.Commit()
+}
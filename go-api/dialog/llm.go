@@ -0,0 +1,15 @@
+package dialog
+
+import "context"
+
+// ChatMessage — одно сообщение истории диалога, передаваемое в LLM backend
+type ChatMessage struct {
+    Role    string // "system", "user" или "assistant"
+    Content string
+}
+
+// LLMBackend — абстракция над конкретным провайдером LLM, позволяющая подменять
+// OpenAI на Anthropic или локальную модель через конфигурацию
+type LLMBackend interface {
+    Complete(ctx context.Context, messages []ChatMessage) (string, error)
+}
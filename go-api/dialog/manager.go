@@ -1,25 +1,78 @@
 package dialog
 
-import "fmt"
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// maxContextTokens — размер скользящего окна истории, передаваемой в LLM
+const maxContextTokens = 3000
 
 // DialogManager описывает интерфейс управления диалогом
 type DialogManager interface {
     HandleMessage(userID string, input string) (string, error)
 }
 
-// DefaultManager — базовая реализация DialogManager
-type DefaultManager struct{}
-
-// NewManager — фабрика для создания менеджера
-func NewManager() DialogManager {
-    return &DefaultManager{}
+// DefaultManager — реализация DialogManager с персистентным контекстом в PostgreSQL,
+// лимитом запросов в Redis и подключаемым LLM backend'ом
+type DefaultManager struct {
+    history    *historyStore
+    limiter    *rateLimiter
+    llm        LLMBackend
+    promptPath string
 }
 
-// HandleMessage — обработка входящего сообщения
-func (m *DefaultManager) HandleMessage(userID string, input string) 
-(string, error) {
-    // TODO: В будущем здесь будет вызов LLM и логика контекста
-    fmt.Printf("📨 [%s] %s\n", userID, input)
-    return "🔁 Ответ будет здесь (заглушка)", nil
+// NewManager создаёт DialogManager поверх PostgreSQL (история диалога) и Redis
+// (лимит запросов), отвечающий через указанный LLM backend
+func NewManager(db *sql.DB, rdb *redis.Client, llm LLMBackend) (DialogManager, error) {
+    history, err := newHistoryStore(db, maxContextTokens)
+    if err != nil {
+        return nil, fmt.Errorf("инициализация истории диалога: %w", err)
+    }
+
+    return &DefaultManager{
+        history:    history,
+        limiter:    newRateLimiter(rdb, 20, time.Minute),
+        llm:        llm,
+        promptPath: defaultPromptPath,
+    }, nil
 }
 
+// HandleMessage — обработка входящего сообщения: проверка лимита, сохранение в
+// историю, вызов LLM с системным промптом и контекстом, сохранение ответа
+func (m *DefaultManager) HandleMessage(userID string, input string) (string, error) {
+    ctx := context.Background()
+
+    allowed, err := m.limiter.allow(ctx, userID)
+    if err != nil {
+        fmt.Printf("⚠️ Ошибка проверки лимита запросов: %v\n", err)
+    } else if !allowed {
+        return "⏳ Слишком много сообщений подряд — подождите немного и напишите снова.", nil
+    }
+
+    if err := m.history.append(ctx, userID, "user", input, m.llm); err != nil {
+        return "", fmt.Errorf("сохранение сообщения пользователя: %w", err)
+    }
+
+    window, err := m.history.window(ctx, userID)
+    if err != nil {
+        return "", fmt.Errorf("чтение контекста диалога: %w", err)
+    }
+
+    messages := append([]ChatMessage{{Role: "system", Content: loadSystemPrompt(m.promptPath)}}, window...)
+
+    reply, err := m.llm.Complete(ctx, messages)
+    if err != nil {
+        return "", fmt.Errorf("обращение к LLM: %w", err)
+    }
+
+    if err := m.history.append(ctx, userID, "assistant", reply, m.llm); err != nil {
+        fmt.Printf("⚠️ Не удалось сохранить ответ ассистента: %v\n", err)
+    }
+
+    return reply, nil
+}
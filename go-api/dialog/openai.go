@@ -0,0 +1,80 @@
+package dialog
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+)
+
+// OpenAIBackend — реализация LLMBackend поверх OpenAI Chat Completions API
+type OpenAIBackend struct {
+    apiKey string
+    model  string
+}
+
+// NewOpenAIBackend создаёт backend на базе OpenAI с указанной моделью.
+// Пустая модель заменяется на gpt-4o-mini.
+func NewOpenAIBackend(apiKey, model string) *OpenAIBackend {
+    if model == "" {
+        model = "gpt-4o-mini"
+    }
+    return &OpenAIBackend{apiKey: apiKey, model: model}
+}
+
+type openAIMessage struct {
+    Role    string `json:"role"`
+    Content string `json:"content"`
+}
+
+type openAIRequest struct {
+    Model    string          `json:"model"`
+    Messages []openAIMessage `json:"messages"`
+}
+
+// Complete отправляет историю сообщений в OpenAI и возвращает текст ответа ассистента
+func (b *OpenAIBackend) Complete(ctx context.Context, messages []ChatMessage) (string, error) {
+    payload := openAIRequest{Model: b.model}
+    for _, m := range messages {
+        payload.Messages = append(payload.Messages, openAIMessage{Role: m.Role, Content: m.Content})
+    }
+
+    body, err := json.Marshal(payload)
+    if err != nil {
+        return "", fmt.Errorf("сериализация запроса к OpenAI: %w", err)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.openai.com/v1/chat/completions", bytes.NewReader(body))
+    if err != nil {
+        return "", err
+    }
+    req.Header.Set("Authorization", "Bearer "+b.apiKey)
+    req.Header.Set("Content-Type", "application/json")
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return "", fmt.Errorf("запрос к OpenAI: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        errBody, _ := io.ReadAll(resp.Body)
+        return "", fmt.Errorf("OpenAI вернул %d: %s", resp.StatusCode, string(errBody))
+    }
+
+    var result struct {
+        Choices []struct {
+            Message openAIMessage `json:"message"`
+        } `json:"choices"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+        return "", fmt.Errorf("разбор ответа OpenAI: %w", err)
+    }
+    if len(result.Choices) == 0 {
+        return "", fmt.Errorf("OpenAI не вернул ни одного варианта ответа")
+    }
+
+    return result.Choices[0].Message.Content, nil
+}
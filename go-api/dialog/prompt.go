@@ -0,0 +1,19 @@
+package dialog
+
+import "os"
+
+// defaultPromptPath — файл с системным промптом (персона продавца, описание каталога).
+// Его можно отредактировать без пересборки и редеплоя сервиса.
+const defaultPromptPath = "dialog/prompts/system.txt"
+
+// fallbackSystemPrompt используется, если defaultPromptPath недоступен
+const fallbackSystemPrompt = "Ты — вежливый продавец-консультант интернет-магазина трикотажа. Помогай клиентам выбрать товар и отвечай по существу."
+
+// loadSystemPrompt читает системный промпт из файла; при ошибке чтения возвращает запасной текст
+func loadSystemPrompt(path string) string {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return fallbackSystemPrompt
+    }
+    return string(data)
+}
@@ -0,0 +1,35 @@
+package dialog
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// rateLimiter — token bucket на Redis (INCR+EXPIRE): не более limit сообщений за window на пользователя
+type rateLimiter struct {
+    rdb    *redis.Client
+    limit  int64
+    window time.Duration
+}
+
+func newRateLimiter(rdb *redis.Client, limit int64, window time.Duration) *rateLimiter {
+    return &rateLimiter{rdb: rdb, limit: limit, window: window}
+}
+
+// allow возвращает true, если пользователь не исчерпал лимит сообщений в текущем окне
+func (rl *rateLimiter) allow(ctx context.Context, userID string) (bool, error) {
+    key := fmt.Sprintf("ratelimit:dialog:%s", userID)
+
+    count, err := rl.rdb.Incr(ctx, key).Result()
+    if err != nil {
+        return false, fmt.Errorf("инкремент счётчика лимита: %w", err)
+    }
+    if count == 1 {
+        rl.rdb.Expire(ctx, key, rl.window)
+    }
+
+    return count <= rl.limit, nil
+}
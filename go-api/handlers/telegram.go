@@ -1,19 +1,28 @@
 package handlers
 
 import (
+    "bytes"
+    "database/sql"
     "encoding/json"
     "fmt"
     "log"
     "net/http"
+
+    "ai_seller/config"
+    "ai_seller/dialog"
+    "ai_seller/jobs"
+
+    "github.com/redis/go-redis/v9"
 )
 
 // TelegramUpdate — структура запроса от Telegram с поддержкой голосовых сообщений
 type TelegramUpdate struct {
     Message struct {
-        Text  string `json:"text"`
-        Voice *Voice `json:"voice,omitempty"`
-        Audio *Audio `json:"audio,omitempty"`
-        Chat  struct {
+        MessageID int64  `json:"message_id"`
+        Text      string `json:"text"`
+        Voice     *Voice `json:"voice,omitempty"`
+        Audio     *Audio `json:"audio,omitempty"`
+        Chat      struct {
             ID int64 `json:"id"`
         } `json:"chat"`
         From struct {
@@ -50,37 +59,105 @@ type Photo struct {
     FileSize     int    `json:"file_size,omitempty"`
 }
 
-// TelegramHandler — базовый HTTP-хендлер для Telegram webhook
-func TelegramHandler(w http.ResponseWriter, r *http.Request) {
-    if r.Method != http.MethodPost {
-        w.WriteHeader(http.StatusMethodNotAllowed)
-        w.Write([]byte("Метод не поддерживается"))
-        return
+// Dependencies — зависимости, необходимые TelegramHandler для обработки сообщений
+type Dependencies struct {
+    Config *config.Config
+    Redis  *redis.Client
+    DB     *sql.DB
+    Dialog dialog.DialogManager
+}
+
+// NewTelegramHandler создаёт HTTP-хендлер для Telegram webhook с заданными зависимостями
+func NewTelegramHandler(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodPost {
+            w.WriteHeader(http.StatusMethodNotAllowed)
+            w.Write([]byte("Метод не поддерживается"))
+            return
+        }
+
+        var update TelegramUpdate
+        if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+            log.Printf("❌ Ошибка разбора запроса Telegram: %v", err)
+            w.WriteHeader(http.StatusBadRequest)
+            return
+        }
+
+        if update.Message.Text != "" {
+            log.Printf("📩 Текстовое сообщение от пользователя %d: %s",
+                update.Message.From.ID, update.Message.Text)
+
+            userID := fmt.Sprintf("%d", update.Message.From.ID)
+            reply, err := deps.Dialog.HandleMessage(userID, update.Message.Text)
+            if err != nil {
+                log.Printf("❌ Ошибка обработки сообщения диалоговым менеджером: %v", err)
+                w.WriteHeader(http.StatusOK)
+                return
+            }
+
+            if err := sendTelegramMessage(deps.Config.TelegramBotToken, update.Message.Chat.ID, reply); err != nil {
+                log.Printf("❌ Ошибка отправки ответа в Telegram: %v", err)
+            }
+            w.WriteHeader(http.StatusOK)
+        } else if update.Message.Voice != nil {
+            log.Printf("🎤 Голосовое сообщение от пользователя %d: файл %s (длительность: %d сек)",
+                update.Message.From.ID, update.Message.Voice.FileID, update.Message.Voice.Duration)
+            enqueueTranscription(deps, update, "voice", update.Message.Voice.FileID, update.Message.Voice.FileUniqueID, update.Message.Voice.Duration, update.Message.Voice.MimeType)
+            fmt.Fprintf(w, "Принято голосовое сообщение: %s", update.Message.Voice.FileID)
+        } else if update.Message.Audio != nil {
+            log.Printf("🎵 Аудио сообщение от пользователя %d: файл %s",
+                update.Message.From.ID, update.Message.Audio.FileID)
+            enqueueTranscription(deps, update, "audio", update.Message.Audio.FileID, update.Message.Audio.FileUniqueID, update.Message.Audio.Duration, update.Message.Audio.MimeType)
+            fmt.Fprintf(w, "Принято аудио сообщение: %s", update.Message.Audio.FileID)
+        } else {
+            log.Printf("❓ Неподдерживаемый тип сообщения от пользователя %d",
+                update.Message.From.ID)
+            fmt.Fprintf(w, "Неподдерживаемый тип сообщения")
+        }
+    }
+}
+
+// enqueueTranscription ставит в очередь задачу распознавания голосового/аудио сообщения
+func enqueueTranscription(deps Dependencies, update TelegramUpdate, kind, fileID, fileUniqueID string, duration int, mimeType string) {
+    task := jobs.Task{
+        Type: "transcribe",
+        Data: jobs.TranscribeTaskData{
+            ChatID:       update.Message.Chat.ID,
+            UserID:       update.Message.From.ID,
+            MessageID:    update.Message.MessageID,
+            FileID:       fileID,
+            FileUniqueID: fileUniqueID,
+            Duration:     duration,
+            Kind:         kind,
+            MimeType:     mimeType,
+        },
+    }
+
+    if err := jobs.PublishTask(deps.Redis, task); err != nil {
+        log.Printf("❌ Не удалось поставить задачу транскрибации в очередь: %v", err)
+    }
+}
+
+// sendTelegramMessage отправляет текстовый ответ пользователю через Telegram Bot API
+func sendTelegramMessage(token string, chatID int64, text string) error {
+    payload, err := json.Marshal(map[string]interface{}{
+        "chat_id": chatID,
+        "text":    text,
+    })
+    if err != nil {
+        return fmt.Errorf("сериализация sendMessage: %w", err)
     }
 
-    var update TelegramUpdate
-    if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
-        log.Printf("❌ Ошибка разбора запроса Telegram: %v", err)
-        w.WriteHeader(http.StatusBadRequest)
-        return
+    url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", token)
+    resp, err := http.Post(url, "application/json", bytes.NewReader(payload))
+    if err != nil {
+        return fmt.Errorf("запрос sendMessage: %w", err)
     }
+    defer resp.Body.Close()
 
-    if update.Message.Text != "" {
-        log.Printf("📩 Текстовое сообщение от пользователя %d: %s", 
-            update.Message.From.ID, update.Message.Text)
-        fmt.Fprintf(w, "Принято текстовое сообщение: %s", update.Message.Text)
-    } else if update.Message.Voice != nil {
-        log.Printf("🎤 Голосовое сообщение от пользователя %d: файл %s (длительность: %d сек)", 
-            update.Message.From.ID, update.Message.Voice.FileID, update.Message.Voice.Duration)
-        fmt.Fprintf(w, "Принято голосовое сообщение: %s", update.Message.Voice.FileID)
-    } else if update.Message.Audio != nil {
-        log.Printf("🎵 Аудио сообщение от пользователя %d: файл %s", 
-            update.Message.From.ID, update.Message.Audio.FileID)
-        fmt.Fprintf(w, "Принято аудио сообщение: %s", update.Message.Audio.FileID)
-    } else {
-        log.Printf("❓ Неподдерживаемый тип сообщения от пользователя %d", 
-            update.Message.From.ID)
-        fmt.Fprintf(w, "Неподдерживаемый тип сообщения")
+    if resp.StatusCode != http.StatusOK {
+        return fmt.Errorf("Telegram sendMessage вернул %d", resp.StatusCode)
     }
+    return nil
 }
 
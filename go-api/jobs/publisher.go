@@ -2,6 +2,8 @@ package jobs
 
 import (
     "context"
+    "crypto/rand"
+    "encoding/hex"
     "encoding/json"
     "fmt"
 
@@ -10,21 +12,37 @@ import (
 
 // Task — структура задачи для очереди
 type Task struct {
+    ID   string      `json:"id"`
     Type string      `json:"type"`
     Data interface{} `json:"data"`
 }
 
-// PublishTask отправляет задачу в Redis-очередь
+// TranscribeTaskData — данные задачи распознавания голосового/аудио сообщения
+type TranscribeTaskData struct {
+    ChatID       int64  `json:"chat_id"`
+    UserID       int64  `json:"user_id"`
+    MessageID    int64  `json:"message_id"`
+    FileID       string `json:"file_id"`
+    FileUniqueID string `json:"file_unique_id"`
+    Duration     int    `json:"duration"`
+    Kind         string `json:"kind"` // "voice" или "audio"
+    MimeType     string `json:"mime_type"`
+}
+
+// PublishTask отправляет задачу в Redis-очередь queue:tasks
 func PublishTask(rdb *redis.Client, task Task) error {
     ctx := context.Background()
 
+    if task.ID == "" {
+        task.ID = newTaskID()
+    }
+
     payload, err := json.Marshal(task)
     if err != nil {
         return fmt.Errorf("ошибка сериализации задачи: %w", err)
     }
 
-    // Отправляем в список (можно заменить на pubsub при необходимости)
-    if err := rdb.LPush(ctx, "queue:tasks", payload).Err(); err != nil {
+    if err := rdb.LPush(ctx, queueKey, payload).Err(); err != nil {
         return fmt.Errorf("ошибка отправки в очередь: %w", err)
     }
 
@@ -32,3 +50,11 @@ func PublishTask(rdb *redis.Client, task Task) error {
     return nil
 }
 
+// newTaskID генерирует случайный идентификатор задачи для отслеживания попыток
+func newTaskID() string {
+    b := make([]byte, 16)
+    if _, err := rand.Read(b); err != nil {
+        return fmt.Sprintf("%x", b)
+    }
+    return hex.EncodeToString(b)
+}
@@ -0,0 +1,220 @@
+package jobs
+
+import (
+    "bytes"
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "io"
+    "mime/multipart"
+    "net/http"
+    "strings"
+    "time"
+)
+
+// maxTranscribeDuration — голосовые/аудио длиннее этого лимита не распознаются
+const maxTranscribeDuration = 10 * time.Minute
+
+func init() {
+    RegisterHandler("transcribe", handleTranscribeTask)
+}
+
+// handleTranscribeTask скачивает голосовое/аудио сообщение, распознаёт его через
+// Whisper, сохраняет результат в PostgreSQL и передаёт текст в DialogManager.
+func handleTranscribeTask(ctx context.Context, deps HandlerDeps, task Task) error {
+    var data TranscribeTaskData
+    encoded, err := json.Marshal(task.Data)
+    if err != nil {
+        return fmt.Errorf("сериализация данных задачи: %w", err)
+    }
+    if err := json.Unmarshal(encoded, &data); err != nil {
+        return fmt.Errorf("разбор данных задачи: %w", err)
+    }
+
+    if time.Duration(data.Duration)*time.Second > maxTranscribeDuration {
+        fmt.Printf("⏱ Сообщение %s длиннее %s — пропускаем\n", data.FileUniqueID, maxTranscribeDuration)
+        return nil
+    }
+
+    cacheKey := "transcribe:hash:" + data.FileUniqueID
+    if cached, err := deps.Redis.Get(ctx, cacheKey).Result(); err == nil && cached != "" {
+        fmt.Printf("♻️ Файл %s уже распознан ранее — используем кэш\n", data.FileUniqueID)
+        forwardTranscript(deps, data, cached)
+        return nil
+    }
+
+    audio, err := downloadTelegramFile(deps.Config.TelegramBotToken, data.FileID)
+    if err != nil {
+        return fmt.Errorf("загрузка файла из Telegram: %w", err)
+    }
+
+    text, err := transcribeAudio(deps.Config.OpenAIKey, audio, data.Kind, data.MimeType)
+    if err != nil {
+        return fmt.Errorf("распознавание речи: %w", err)
+    }
+
+    if err := saveTranscript(deps.DB, data, text); err != nil {
+        fmt.Printf("❌ Ошибка сохранения расшифровки: %v\n", err)
+    }
+
+    if err := deps.Redis.Set(ctx, cacheKey, text, 30*24*time.Hour).Err(); err != nil {
+        fmt.Printf("⚠️ Не удалось закэшировать расшифровку: %v\n", err)
+    }
+
+    forwardTranscript(deps, data, text)
+    return nil
+}
+
+// telegramFileResponse — ответ метода getFile Telegram Bot API
+type telegramFileResponse struct {
+    OK     bool `json:"ok"`
+    Result struct {
+        FilePath string `json:"file_path"`
+    } `json:"result"`
+}
+
+// downloadTelegramFile получает путь к файлу через getFile и скачивает его содержимое
+func downloadTelegramFile(token, fileID string) ([]byte, error) {
+    getFileURL := fmt.Sprintf("https://api.telegram.org/bot%s/getFile?file_id=%s", token, fileID)
+    resp, err := http.Get(getFileURL)
+    if err != nil {
+        return nil, fmt.Errorf("запрос getFile: %w", err)
+    }
+    defer resp.Body.Close()
+
+    var fileResp telegramFileResponse
+    if err := json.NewDecoder(resp.Body).Decode(&fileResp); err != nil {
+        return nil, fmt.Errorf("разбор ответа getFile: %w", err)
+    }
+    if !fileResp.OK || fileResp.Result.FilePath == "" {
+        return nil, fmt.Errorf("Telegram не вернул путь к файлу")
+    }
+
+    downloadURL := fmt.Sprintf("https://api.telegram.org/file/bot%s/%s", token, fileResp.Result.FilePath)
+    fileBody, err := http.Get(downloadURL)
+    if err != nil {
+        return nil, fmt.Errorf("скачивание файла: %w", err)
+    }
+    defer fileBody.Body.Close()
+
+    return io.ReadAll(fileBody.Body)
+}
+
+// transcribeAudio отправляет аудио в OpenAI Whisper и возвращает распознанный текст
+func transcribeAudio(apiKey string, audio []byte, kind, mimeType string) (string, error) {
+    ext := "ogg"
+    if kind == "audio" {
+        ext = "mp3"
+        if strings.Contains(mimeType, "mp4") || strings.Contains(mimeType, "m4a") {
+            ext = "m4a"
+        }
+    }
+
+    var body bytes.Buffer
+    writer := multipart.NewWriter(&body)
+
+    part, err := writer.CreateFormFile("file", "audio."+ext)
+    if err != nil {
+        return "", err
+    }
+    if _, err := part.Write(audio); err != nil {
+        return "", err
+    }
+    if err := writer.WriteField("model", "whisper-1"); err != nil {
+        return "", err
+    }
+    if err := writer.Close(); err != nil {
+        return "", err
+    }
+
+    req, err := http.NewRequest(http.MethodPost, "https://api.openai.com/v1/audio/transcriptions", &body)
+    if err != nil {
+        return "", err
+    }
+    req.Header.Set("Authorization", "Bearer "+apiKey)
+    req.Header.Set("Content-Type", writer.FormDataContentType())
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return "", err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        errBody, _ := io.ReadAll(resp.Body)
+        return "", fmt.Errorf("OpenAI вернул %d: %s", resp.StatusCode, string(errBody))
+    }
+
+    var result struct {
+        Text string `json:"text"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+        return "", err
+    }
+
+    return result.Text, nil
+}
+
+// saveTranscript сохраняет соответствие message_id → расшифрованный текст
+func saveTranscript(db *sql.DB, data TranscribeTaskData, text string) error {
+    _, err := db.Exec(`
+        CREATE TABLE IF NOT EXISTS voice_transcripts (
+            message_id     BIGINT PRIMARY KEY,
+            chat_id        BIGINT NOT NULL,
+            user_id        BIGINT NOT NULL,
+            file_unique_id TEXT NOT NULL,
+            text           TEXT NOT NULL,
+            created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+        )
+    `)
+    if err != nil {
+        return fmt.Errorf("создание таблицы voice_transcripts: %w", err)
+    }
+
+    _, err = db.Exec(
+        `INSERT INTO voice_transcripts (message_id, chat_id, user_id, file_unique_id, text)
+         VALUES ($1, $2, $3, $4, $5)
+         ON CONFLICT (message_id) DO UPDATE SET text = EXCLUDED.text`,
+        data.MessageID, data.ChatID, data.UserID, data.FileUniqueID, text,
+    )
+    return err
+}
+
+// forwardTranscript передаёт распознанный текст в DialogManager как обычное сообщение
+// пользователя и отправляет полученный ответ обратно в Telegram
+func forwardTranscript(deps HandlerDeps, data TranscribeTaskData, text string) {
+    userID := fmt.Sprintf("%d", data.UserID)
+    reply, err := deps.Dialog.HandleMessage(userID, text)
+    if err != nil {
+        fmt.Printf("❌ Ошибка обработки расшифрованного текста: %v\n", err)
+        return
+    }
+
+    if err := sendTelegramMessage(deps.Config.TelegramBotToken, data.ChatID, reply); err != nil {
+        fmt.Printf("❌ Ошибка отправки ответа в Telegram: %v\n", err)
+    }
+}
+
+// sendTelegramMessage отправляет текстовый ответ пользователю через Telegram Bot API
+func sendTelegramMessage(token string, chatID int64, text string) error {
+    payload, err := json.Marshal(map[string]interface{}{
+        "chat_id": chatID,
+        "text":    text,
+    })
+    if err != nil {
+        return fmt.Errorf("сериализация sendMessage: %w", err)
+    }
+
+    url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", token)
+    resp, err := http.Post(url, "application/json", bytes.NewReader(payload))
+    if err != nil {
+        return fmt.Errorf("запрос sendMessage: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return fmt.Errorf("Telegram sendMessage вернул %d", resp.StatusCode)
+    }
+    return nil
+}
@@ -1,18 +1,262 @@
 package jobs
 
 import (
+    "context"
+    "database/sql"
+    "encoding/json"
     "fmt"
+    "math"
+    "strconv"
+    "strings"
     "time"
+
+    "ai_seller/config"
+    "ai_seller/dialog"
+
+    "github.com/redis/go-redis/v9"
+)
+
+const (
+    queueKey         = "queue:tasks"
+    delayedKey       = "queue:delayed"
+    deadKey          = "queue:dead"
+    attemptsHashKey  = "queue:attempts"
+    processingPrefix = "processing:"
+    heartbeatPrefix  = "heartbeat:"
 )
 
-// StartWorker запускает фоновый воркер
-func StartWorker() {
+// HandlerDeps — общие зависимости, доступные обработчикам задач
+type HandlerDeps struct {
+    Config *config.Config
+    Redis  *redis.Client
+    DB     *sql.DB
+    Dialog dialog.DialogManager
+}
+
+// TaskHandler обрабатывает задачу конкретного типа
+type TaskHandler func(ctx context.Context, deps HandlerDeps, task Task) error
+
+var handlers = map[string]TaskHandler{}
+
+// RegisterHandler регистрирует обработчик для типа задачи. Вызывается из init()
+// файлов, реализующих конкретные задачи (см. transcribe.go).
+func RegisterHandler(taskType string, handler TaskHandler) {
+    handlers[taskType] = handler
+}
+
+// StartWorker поднимает пул воркеров, читающих queue:tasks по схеме reliable queue
+// (BRPOPLPUSH в processing:<worker_id>), а также планировщик отложенных задач и
+// уборщика, возвращающего в очередь задачи воркеров, переставших отвечать.
+func StartWorker(cfg *config.Config, rdb *redis.Client, db *sql.DB, dm dialog.DialogManager) {
+    deps := HandlerDeps{Config: cfg, Redis: rdb, DB: db, Dialog: dm}
+
+    for i := 0; i < cfg.WorkerCount; i++ {
+        workerID := fmt.Sprintf("worker-%d", i)
+        go runWorker(workerID, cfg, rdb, deps)
+    }
+
+    go runScheduler(rdb)
+    go runJanitor(cfg, rdb)
+
+    fmt.Printf("🛠 Запущено воркеров: %d\n", cfg.WorkerCount)
+}
+
+// runWorker — основной цикл одного воркера: читает задачи, шлёт heartbeat и обрабатывает их
+func runWorker(workerID string, cfg *config.Config, rdb *redis.Client, deps HandlerDeps) {
+    ctx := context.Background()
+    processingKey := processingPrefix + workerID
+    heartbeatKey := heartbeatPrefix + workerID
+    visibility := time.Duration(cfg.VisibilityTimeoutSec) * time.Second
+
+    rdb.Set(ctx, heartbeatKey, "1", visibility)
     go func() {
-        for {
-            // TODO: в будущем — чтение из Redis очереди
-            fmt.Println("🛠 Воркер активен — жду задачи...")
-            time.Sleep(10 * time.Second)
+        ticker := time.NewTicker(visibility / 2)
+        defer ticker.Stop()
+        for range ticker.C {
+            rdb.Set(ctx, heartbeatKey, "1", visibility)
+        }
+    }()
+
+    for {
+        payload, err := rdb.BRPopLPush(ctx, queueKey, processingKey, 5*time.Second).Result()
+        if err != nil {
+            if err != redis.Nil {
+                fmt.Printf("❌ [%s] ошибка чтения очереди: %v\n", workerID, err)
+            }
+            continue
+        }
+
+        processPayload(ctx, cfg, rdb, deps, processingKey, payload)
+    }
+}
+
+// processPayload разбирает задачу, находит обработчик и обрабатывает сбои/панику
+func processPayload(ctx context.Context, cfg *config.Config, rdb *redis.Client, deps HandlerDeps, processingKey, payload string) {
+    defer func() {
+        if r := recover(); r != nil {
+            fmt.Printf("❌ Паника при обработке задачи: %v\n", r)
+            handleFailure(ctx, cfg, rdb, processingKey, payload, fmt.Errorf("panic: %v", r))
         }
     }()
+
+    var task Task
+    if err := json.Unmarshal([]byte(payload), &task); err != nil {
+        fmt.Printf("❌ Ошибка разбора задачи: %v\n", err)
+        rdb.LRem(ctx, processingKey, 1, payload)
+        return
+    }
+
+    handler, ok := handlers[task.Type]
+    if !ok {
+        fmt.Printf("⚠️ Нет обработчика для типа задачи: %s\n", task.Type)
+        rdb.LRem(ctx, processingKey, 1, payload)
+        return
+    }
+
+    if err := handler(ctx, deps, task); err != nil {
+        handleFailure(ctx, cfg, rdb, processingKey, payload, err)
+        return
+    }
+
+    rdb.LRem(ctx, processingKey, 1, payload)
+    rdb.HDel(ctx, attemptsHashKey, task.ID)
 }
 
+// handleFailure учитывает попытку, снимает задачу из processing-списка и либо
+// переносит её в очередь отложенных с экспоненциальной задержкой, либо в DLQ
+func handleFailure(ctx context.Context, cfg *config.Config, rdb *redis.Client, processingKey, payload string, cause error) {
+    rdb.LRem(ctx, processingKey, 1, payload)
+
+    var task Task
+    if err := json.Unmarshal([]byte(payload), &task); err != nil {
+        return
+    }
+
+    attempts, _ := rdb.HIncrBy(ctx, attemptsHashKey, task.ID, 1).Result()
+    fmt.Printf("❌ Задача %s (%s) провалилась (попытка %d): %v\n", task.ID, task.Type, attempts, cause)
+
+    if int(attempts) >= cfg.MaxTaskAttempts {
+        fmt.Printf("☠️ Задача %s ушла в DLQ после %d попыток\n", task.ID, attempts)
+        rdb.LPush(ctx, deadKey, payload)
+        rdb.HDel(ctx, attemptsHashKey, task.ID)
+        return
+    }
+
+    backoff := time.Duration(math.Pow(2, float64(attempts))) * time.Second
+    runAt := float64(time.Now().Add(backoff).Unix())
+    rdb.ZAdd(ctx, delayedKey, redis.Z{Score: runAt, Member: payload})
+}
+
+// runScheduler переносит созревшие отложенные задачи обратно в очередь
+func runScheduler(rdb *redis.Client) {
+    ctx := context.Background()
+    ticker := time.NewTicker(time.Second)
+    defer ticker.Stop()
+
+    for range ticker.C {
+        now := strconv.FormatInt(time.Now().Unix(), 10)
+        ready, err := rdb.ZRangeByScore(ctx, delayedKey, &redis.ZRangeBy{Min: "-inf", Max: now}).Result()
+        if err != nil {
+            continue
+        }
+
+        for _, payload := range ready {
+            if removed, err := rdb.ZRem(ctx, delayedKey, payload).Result(); err == nil && removed > 0 {
+                rdb.LPush(ctx, queueKey, payload)
+            }
+        }
+    }
+}
+
+// scanKeys возвращает все ключи, подходящие под pattern, итерируясь через SCAN —
+// в отличие от KEYS не блокирует Redis целиком на больших базах.
+func scanKeys(ctx context.Context, rdb *redis.Client, pattern string) ([]string, error) {
+    var keys []string
+    iter := rdb.Scan(ctx, 0, pattern, 100).Iterator()
+    for iter.Next(ctx) {
+        keys = append(keys, iter.Val())
+    }
+    if err := iter.Err(); err != nil {
+        return nil, err
+    }
+    return keys, nil
+}
+
+// runJanitor периодически проверяет, что каждому непустому processing:<id> списку
+// соответствует живой heartbeat воркера, и возвращает задачи мёртвых воркеров в очередь
+func runJanitor(cfg *config.Config, rdb *redis.Client) {
+    ctx := context.Background()
+    interval := time.Duration(cfg.VisibilityTimeoutSec) * time.Second
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for range ticker.C {
+        keys, err := scanKeys(ctx, rdb, processingPrefix+"*")
+        if err != nil {
+            continue
+        }
+
+        for _, key := range keys {
+            workerID := strings.TrimPrefix(key, processingPrefix)
+            alive, err := rdb.Exists(ctx, heartbeatPrefix+workerID).Result()
+            if err != nil || alive > 0 {
+                continue
+            }
+
+            items, err := rdb.LRange(ctx, key, 0, -1).Result()
+            if err != nil {
+                continue
+            }
+            for _, payload := range items {
+                if rdb.LRem(ctx, key, 1, payload).Val() > 0 {
+                    fmt.Printf("🧹 Воркер %s не отвечает — задача возвращена в очередь\n", workerID)
+                    rdb.LPush(ctx, queueKey, payload)
+                }
+            }
+        }
+    }
+}
+
+// QueueStats — снимок глубины очередей и количества задач в обработке, для дашборда
+type QueueStats struct {
+    Pending  int64 `json:"pending"`
+    Delayed  int64 `json:"delayed"`
+    Dead     int64 `json:"dead"`
+    InFlight int64 `json:"in_flight"`
+}
+
+// Stats возвращает текущую глубину очередей задач
+func Stats(ctx context.Context, rdb *redis.Client) (QueueStats, error) {
+    var stats QueueStats
+
+    pending, err := rdb.LLen(ctx, queueKey).Result()
+    if err != nil {
+        return stats, fmt.Errorf("глубина queue:tasks: %w", err)
+    }
+    delayed, err := rdb.ZCard(ctx, delayedKey).Result()
+    if err != nil {
+        return stats, fmt.Errorf("глубина queue:delayed: %w", err)
+    }
+    dead, err := rdb.LLen(ctx, deadKey).Result()
+    if err != nil {
+        return stats, fmt.Errorf("глубина queue:dead: %w", err)
+    }
+
+    keys, err := scanKeys(ctx, rdb, processingPrefix+"*")
+    if err != nil {
+        return stats, fmt.Errorf("список processing-очередей: %w", err)
+    }
+    var inFlight int64
+    for _, key := range keys {
+        n, err := rdb.LLen(ctx, key).Result()
+        if err == nil {
+            inFlight += n
+        }
+    }
+
+    stats.Pending = pending
+    stats.Delayed = delayed
+    stats.Dead = dead
+    stats.InFlight = inFlight
+    return stats, nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
     "context"
+    "database/sql"
     "fmt"
     "log"
     "net/http"
@@ -11,35 +12,77 @@ import (
     "time"
 
     "ai_seller/config"
-    "ai_seller/storage"
+    "ai_seller/dashboard"
+    "ai_seller/dialog"
     "ai_seller/handlers"
+    "ai_seller/jobs"
+    "ai_seller/middleware"
+    "ai_seller/storage"
+    "ai_seller/tgclient"
 
     "github.com/redis/go-redis/v9"
 )
 
-func initializeDependencies() (*config.Config, *redis.Client, error) {
+func initializeDependencies() (*config.Config, *redis.Client, *sql.DB, error) {
     cfg := config.LoadConfig()
     fmt.Printf("🔧 Конфигурация загружена: %+v\n", cfg)
 
     db := storage.ConnectPostgres(cfg.PostgresDSN)
     rdb := storage.ConnectRedis(cfg.RedisAddr)
-    
+
     // Verify connections
     if db == nil {
-        return nil, nil, fmt.Errorf("failed to connect to PostgreSQL")
+        return nil, nil, nil, fmt.Errorf("failed to connect to PostgreSQL")
     }
     if rdb == nil {
-        return nil, nil, fmt.Errorf("failed to connect to Redis")
+        return nil, nil, nil, fmt.Errorf("failed to connect to Redis")
+    }
+
+    return cfg, rdb, db, nil
+}
+
+// cachedTables — таблицы, для которых включена Redis-инвалидация через LISTEN/NOTIFY.
+// product_catalog и permissions пока не созданы в схеме: storage.EnableCacheInvalidation
+// проверяет существование таблицы через to_regclass и молча пропускает отсутствующую,
+// так что повторный вызов при следующем старте сервиса сам заведёт триггер, как только
+// соответствующая таблица появится.
+var cachedTables = []struct {
+    name     string
+    idColumn string
+}{
+    {"users", "username"},
+    {"product_catalog", "id"},
+    {"permissions", "id"},
+}
+
+// enableCacheInvalidation заводит триггеры pg_notify('cache_invalidate', ...) на таблицах
+// из cachedTables, иначе storage.StartCacheListener слушает канал, в который никто не пишет.
+func enableCacheInvalidation(db *sql.DB) {
+    for _, t := range cachedTables {
+        if err := storage.EnableCacheInvalidation(db, t.name, t.idColumn); err != nil {
+            log.Printf("⚠️ Не удалось настроить инвалидацию кэша для %s: %v", t.name, err)
+        }
     }
-    
-    return cfg, rdb, nil
 }
 
-func setupRoutes() http.Handler {
+func setupRoutes(cfg *config.Config, rdb *redis.Client, db *sql.DB, dm dialog.DialogManager, oidcVerifier *middleware.OIDCVerifier) http.Handler {
     mux := http.NewServeMux()
 
     // Telegram webhook endpoint
-    mux.HandleFunc("/telegram", handlers.TelegramHandler)
+    mux.HandleFunc("/telegram", handlers.NewTelegramHandler(handlers.Dependencies{
+        Config: cfg,
+        Redis:  rdb,
+        DB:     db,
+        Dialog: dm,
+    }))
+
+    // Дашборд со статусом сервиса и метриками очереди задач — только для авторизованного персонала.
+    // Если настроен OIDC, пропускаем через него; иначе остаёмся в статическом режиме по API_KEY.
+    dashboardAuth := middleware.AuthMiddleware
+    if oidcVerifier != nil {
+        dashboardAuth = oidcVerifier.Middleware
+    }
+    mux.Handle("/dashboard", dashboardAuth(dashboard.NewHandler(rdb)))
 
     // Тестовый health check
     mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -49,17 +92,79 @@ func setupRoutes() http.Handler {
     return mux
 }
 
+// startMTProtoClient поднимает TDLib-клиента и перенаправляет входящие сообщения в dm.
+// Возвращает nil, если TELEGRAM_MODE не равен "mtproto".
+func startMTProtoClient(ctx context.Context, cfg *config.Config, dm dialog.DialogManager) (*tgclient.Client, error) {
+    if cfg.TelegramMode != "mtproto" {
+        return nil, nil
+    }
+
+    tc, err := tgclient.New(cfg)
+    if err != nil {
+        return nil, fmt.Errorf("запуск TDLib-клиента: %w", err)
+    }
+
+    go tc.Listen(ctx)
+
+    go func() {
+        for update := range tc.Updates {
+            userID := fmt.Sprintf("%d", update.UserID)
+            reply, err := dm.HandleMessage(userID, update.Text)
+            if err != nil {
+                log.Printf("❌ Ошибка обработки сообщения MTProto: %v", err)
+                continue
+            }
+
+            if err := tc.SendMessage(update.ChatID, reply); err != nil {
+                log.Printf("❌ Ошибка отправки ответа через TDLib: %v", err)
+            }
+        }
+    }()
+
+    fmt.Println("📡 TDLib-клиент запущен (режим mtproto)")
+    return tc, nil
+}
+
 func main() {
     fmt.Println("🚀 Запуск AI-продавца...")
 
-    cfg, _, err := initializeDependencies()
+    cfg, rdb, db, err := initializeDependencies()
     if err != nil {
         log.Fatalf("❌ Ошибка инициализации: %v", err)
     }
 
+    llmBackend, err := dialog.NewLLMBackend(cfg)
+    if err != nil {
+        log.Fatalf("❌ Ошибка выбора LLM backend'а: %v", err)
+    }
+    dm, err := dialog.NewManager(db, rdb, llmBackend)
+    if err != nil {
+        log.Fatalf("❌ Ошибка инициализации диалогового менеджера: %v", err)
+    }
+    jobs.StartWorker(cfg, rdb, db, dm)
+
+    if err := middleware.EnsureUsersSchema(db); err != nil {
+        log.Fatalf("❌ Ошибка создания таблицы users: %v", err)
+    }
+    enableCacheInvalidation(db)
+    go storage.StartCacheListener(cfg.PostgresDSN, rdb)
+
+    oidcVerifier, err := middleware.NewOIDCVerifier(context.Background(), cfg, db, rdb)
+    if err != nil {
+        log.Fatalf("❌ Ошибка инициализации OIDC: %v", err)
+    }
+
+    mtprotoCtx, stopMTProto := context.WithCancel(context.Background())
+    defer stopMTProto()
+
+    tc, err := startMTProtoClient(mtprotoCtx, cfg, dm)
+    if err != nil {
+        log.Fatalf("❌ Ошибка запуска MTProto-клиента: %v", err)
+    }
+
     srv := &http.Server{
         Addr:    ":" + cfg.Port,
-        Handler: setupRoutes(),
+        Handler: setupRoutes(cfg, rdb, db, dm, oidcVerifier),
     }
 
     stop := make(chan os.Signal, 1)
@@ -75,6 +180,13 @@ func main() {
     <-stop
     fmt.Println("\n⏳ Завершение работы...")
 
+    stopMTProto()
+    if tc != nil {
+        if err := tc.Close(); err != nil {
+            log.Printf("⚠️ Ошибка завершения TDLib-клиента: %v", err)
+        }
+    }
+
     ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
     defer cancel()
 
@@ -1,18 +1,175 @@
 package middleware
 
 import (
+    "context"
+    "database/sql"
+    "fmt"
+    "log"
     "net/http"
     "os"
     "strings"
+
+    "ai_seller/config"
+    "ai_seller/storage"
+
+    "github.com/coreos/go-oidc/v3/oidc"
+    "github.com/lib/pq"
+    "github.com/redis/go-redis/v9"
 )
 
-// AuthMiddleware проверяет заголовок Authorization
+type contextKey string
+
+const userContextKey contextKey = "auth_user"
+
+// AuthenticatedUser — сведения о пользователе, извлечённые из OIDC-токена
+type AuthenticatedUser struct {
+    Username string
+    Groups   []string
+}
+
+// UserFromContext возвращает пользователя, аутентифицированного через OIDC, если он есть в контексте
+func UserFromContext(ctx context.Context) (AuthenticatedUser, bool) {
+    user, ok := ctx.Value(userContextKey).(AuthenticatedUser)
+    return user, ok
+}
+
+// OIDCVerifier проверяет Bearer JWT против JWKS провайдера, извлекает пользователя/группы
+// по настраиваемым claim'ам и, при включённом автоонбординге, заводит/обновляет строку в users.
+type OIDCVerifier struct {
+    cfg      *config.Config
+    db       *sql.DB
+    rdb      *redis.Client
+    verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCVerifier получает discovery-документ провайдера по cfg.OIDCIssuer и готовит
+// верификатор с кэшируемыми ключами JWKS. Возвращает (nil, nil), если OIDC не настроен —
+// в этом случае остаётся доступен только статический режим AuthMiddleware.
+func NewOIDCVerifier(ctx context.Context, cfg *config.Config, db *sql.DB, rdb *redis.Client) (*OIDCVerifier, error) {
+    if cfg.OIDCIssuer == "" {
+        return nil, nil
+    }
+
+    provider, err := oidc.NewProvider(ctx, cfg.OIDCIssuer)
+    if err != nil {
+        return nil, fmt.Errorf("получение discovery-документа OIDC: %w", err)
+    }
+
+    if err := EnsureUsersSchema(db); err != nil {
+        return nil, err
+    }
+
+    return &OIDCVerifier{
+        cfg:      cfg,
+        db:       db,
+        rdb:      rdb,
+        verifier: provider.Verifier(&oidc.Config{ClientID: cfg.OIDCClientID}),
+    }, nil
+}
+
+// Middleware проверяет Bearer JWT, извлекает пользователя и группы, при необходимости
+// онбордит пользователя и кладёт AuthenticatedUser в контекст запроса.
+func (v *OIDCVerifier) Middleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        authHeader := r.Header.Get("Authorization")
+        if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+            http.Error(w, "🚫 Нет токена авторизации", http.StatusUnauthorized)
+            return
+        }
+
+        rawToken := strings.TrimPrefix(authHeader, "Bearer ")
+        idToken, err := v.verifier.Verify(r.Context(), rawToken)
+        if err != nil {
+            http.Error(w, "🚫 Неверный токен", http.StatusForbidden)
+            return
+        }
+
+        var claims map[string]interface{}
+        if err := idToken.Claims(&claims); err != nil {
+            http.Error(w, "🚫 Не удалось разобрать claims токена", http.StatusForbidden)
+            return
+        }
+
+        username, _ := claims[v.cfg.OIDCUsernameClaim].(string)
+        if username == "" {
+            http.Error(w, "🚫 В токене нет claim'а с именем пользователя", http.StatusForbidden)
+            return
+        }
+        groups := extractGroups(claims[v.cfg.OIDCGroupsClaim])
+
+        if v.cfg.OIDCAutoOnboard {
+            if err := onboardUser(r.Context(), v.db, username, groups); err != nil {
+                log.Printf("❌ Ошибка онбординга пользователя %s: %v", username, err)
+            }
+        }
+
+        // Профиль читаем через кэш CachedGet: он заполняется только при чтении, onboardUser
+        // пишет лишь в PostgreSQL и кэш не обновляет. Ключ cache:users:<username> совпадает
+        // с тем, что удаляет триггер cache_invalidate_users при изменении строки в onboardUser.
+        if profile, err := storage.GetUserProfile(r.Context(), v.db, v.rdb, username); err != nil {
+            log.Printf("⚠️ Не удалось получить профиль пользователя %s: %v", username, err)
+        } else {
+            groups = profile.Groups
+        }
+
+        user := AuthenticatedUser{Username: username, Groups: groups}
+        ctx := context.WithValue(r.Context(), userContextKey, user)
+        next.ServeHTTP(w, r.WithContext(ctx))
+    })
+}
+
+func extractGroups(raw interface{}) []string {
+    list, ok := raw.([]interface{})
+    if !ok {
+        return nil
+    }
+
+    groups := make([]string, 0, len(list))
+    for _, item := range list {
+        if s, ok := item.(string); ok {
+            groups = append(groups, s)
+        }
+    }
+    return groups
+}
+
+// EnsureUsersSchema создаёт таблицу users, если она ещё не существует. Вызывается при
+// старте (до установки триггера инвалидации кэша) и из onboardUser — идемпотентна.
+func EnsureUsersSchema(db *sql.DB) error {
+    _, err := db.Exec(`
+        CREATE TABLE IF NOT EXISTS users (
+            username   TEXT PRIMARY KEY,
+            groups     TEXT[] NOT NULL DEFAULT '{}',
+            created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+            updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+        )
+    `)
+    if err != nil {
+        return fmt.Errorf("создание таблицы users: %w", err)
+    }
+    return nil
+}
+
+func onboardUser(ctx context.Context, db *sql.DB, username string, groups []string) error {
+    if err := EnsureUsersSchema(db); err != nil {
+        return err
+    }
+
+    _, err := db.ExecContext(ctx, `
+        INSERT INTO users (username, groups)
+        VALUES ($1, $2)
+        ON CONFLICT (username) DO UPDATE SET groups = EXCLUDED.groups, updated_at = now()
+    `, username, pq.Array(groups))
+    return err
+}
+
+// AuthMiddleware — статический режим проверки по общему API_KEY. Используется там, где
+// OIDC не настроен, и для bot-to-bot вызовов, где выдавать персональный JWT не нужно.
 func AuthMiddleware(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         authHeader := r.Header.Get("Authorization")
         if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-            http.Error(w, "🚫 Нет токена авторизации", 
-http.StatusUnauthorized)
+            http.Error(w, "🚫 Нет токена авторизации", http.StatusUnauthorized)
             return
         }
 
@@ -24,8 +181,6 @@ http.StatusUnauthorized)
             return
         }
 
-        // Всё хорошо — пропускаем дальше
         next.ServeHTTP(w, r)
     })
 }
-
@@ -0,0 +1,229 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "log"
+    "time"
+
+    "github.com/lib/pq"
+    "github.com/redis/go-redis/v9"
+)
+
+// cacheKeyPrefix — общий префикс ключей, инвалидируемых через LISTEN/NOTIFY
+const cacheKeyPrefix = "cache:"
+
+// invalidationPayload — тело уведомления, публикуемого триггерами pg_notify('cache_invalidate', ...)
+type invalidationPayload struct {
+    Table string          `json:"table"`
+    ID    json.RawMessage `json:"id"`
+}
+
+// EnableCacheInvalidation создаёт (или обновляет) триггер, публикующий pg_notify('cache_invalidate', ...)
+// при вставке/изменении/удалении строк таблицы table. idColumn — колонка, однозначно
+// определяющая строку (не обязательно "id"), её значение становится частью ключа кэша.
+// Если таблица ещё не создана, это не ошибка — триггер просто не заводится, и вызов
+// можно безопасно повторить позже (например, при каждом старте сервиса).
+func EnableCacheInvalidation(db *sql.DB, table, idColumn string) error {
+    var regclass sql.NullString
+    if err := db.QueryRow(`SELECT to_regclass($1)`, table).Scan(&regclass); err != nil {
+        return fmt.Errorf("проверка существования таблицы %s: %w", table, err)
+    }
+    if !regclass.Valid {
+        return nil
+    }
+
+    funcName := pq.QuoteIdentifier("notify_cache_invalidate_" + table)
+    triggerName := pq.QuoteIdentifier("cache_invalidate_" + table)
+    quotedTable := pq.QuoteIdentifier(table)
+    quotedIDColumn := pq.QuoteIdentifier(idColumn)
+
+    _, err := db.Exec(fmt.Sprintf(`
+        CREATE OR REPLACE FUNCTION %s() RETURNS trigger AS $$
+        BEGIN
+            PERFORM pg_notify('cache_invalidate', json_build_object(
+                'table', TG_TABLE_NAME,
+                'id', COALESCE(NEW.%s, OLD.%s)
+            )::text);
+            RETURN COALESCE(NEW, OLD);
+        END;
+        $$ LANGUAGE plpgsql;
+    `, funcName, quotedIDColumn, quotedIDColumn))
+    if err != nil {
+        return fmt.Errorf("создание функции триггера для %s: %w", table, err)
+    }
+
+    _, err = db.Exec(fmt.Sprintf(`DROP TRIGGER IF EXISTS %s ON %s`, triggerName, quotedTable))
+    if err != nil {
+        return fmt.Errorf("удаление старого триггера для %s: %w", table, err)
+    }
+
+    _, err = db.Exec(fmt.Sprintf(`
+        CREATE TRIGGER %s
+        AFTER INSERT OR UPDATE OR DELETE ON %s
+        FOR EACH ROW EXECUTE FUNCTION %s()
+    `, triggerName, quotedTable, funcName))
+    if err != nil {
+        return fmt.Errorf("создание триггера для %s: %w", table, err)
+    }
+
+    return nil
+}
+
+// StartCacheListener подписывается на канал cache_invalidate PostgreSQL и удаляет
+// соответствующие ключи в Redis. При потере/восстановлении соединения с БД сбрасывает
+// весь кэш целиком, чтобы не отдавать устаревшие данные, накопленные за время разрыва.
+func StartCacheListener(dsn string, rdb *redis.Client) {
+    listener := pq.NewListener(dsn, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+        if err != nil {
+            log.Printf("❌ Ошибка PostgreSQL listener: %v", err)
+        }
+        if ev == pq.ListenerEventDisconnected || ev == pq.ListenerEventReconnected {
+            flushCache(rdb)
+        }
+    })
+
+    if err := listener.Listen("cache_invalidate"); err != nil {
+        log.Printf("❌ Не удалось подписаться на канал cache_invalidate: %v", err)
+        return
+    }
+
+    fmt.Println("👂 Слушаю уведомления cache_invalidate")
+
+    ctx := context.Background()
+    for notification := range listener.Notify {
+        if notification == nil {
+            // nil-уведомление сигнализирует о переподключении — состояние уже сброшено в колбэке выше
+            continue
+        }
+        handleInvalidation(ctx, rdb, notification.Extra)
+    }
+}
+
+func handleInvalidation(ctx context.Context, rdb *redis.Client, payload string) {
+    var msg invalidationPayload
+    if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+        log.Printf("❌ Ошибка разбора уведомления cache_invalidate: %v", err)
+        return
+    }
+
+    id, err := decodeNotificationID(msg.ID)
+    if err != nil {
+        log.Printf("❌ Ошибка разбора id в уведомлении cache_invalidate: %v", err)
+        return
+    }
+
+    key := cacheKeyPrefix + msg.Table + ":" + id
+    if err := rdb.Del(ctx, key).Err(); err != nil {
+        log.Printf("❌ Ошибка удаления ключа %s: %v", key, err)
+    }
+}
+
+// decodeNotificationID приводит id из payload'а триггера к тому же текстовому виду, что
+// использует CachedGet при формировании ключа. json_build_object отдаёт текстовые id как
+// JSON-строки в кавычках (`"alice"`), а числовые — как голые числа (`42`); string(msg.ID)
+// сохранял кавычки и ключ никогда не совпадал с реальным — отсюда и это декодирование.
+func decodeNotificationID(raw json.RawMessage) (string, error) {
+    var asString string
+    if err := json.Unmarshal(raw, &asString); err == nil {
+        return asString, nil
+    }
+
+    var asNumber json.Number
+    if err := json.Unmarshal(raw, &asNumber); err == nil {
+        return asNumber.String(), nil
+    }
+
+    return "", fmt.Errorf("неподдерживаемый тип id: %s", string(raw))
+}
+
+// scanKeys возвращает все ключи, подходящие под pattern, итерируясь через SCAN —
+// в отличие от KEYS не блокирует Redis целиком на больших базах.
+func scanKeys(ctx context.Context, rdb *redis.Client, pattern string) ([]string, error) {
+    var keys []string
+    iter := rdb.Scan(ctx, 0, pattern, 100).Iterator()
+    for iter.Next(ctx) {
+        keys = append(keys, iter.Val())
+    }
+    if err := iter.Err(); err != nil {
+        return nil, err
+    }
+    return keys, nil
+}
+
+func flushCache(rdb *redis.Client) {
+    ctx := context.Background()
+
+    keys, err := scanKeys(ctx, rdb, cacheKeyPrefix+"*")
+    if err != nil {
+        log.Printf("❌ Ошибка получения ключей кэша для сброса: %v", err)
+        return
+    }
+    if len(keys) == 0 {
+        return
+    }
+
+    if err := rdb.Del(ctx, keys...).Err(); err != nil {
+        log.Printf("❌ Ошибка сброса кэша: %v", err)
+        return
+    }
+    fmt.Printf("🔄 Соединение с PostgreSQL потеряно/восстановлено — сброшено %d ключей кэша\n", len(keys))
+}
+
+// CachedGet читает значение по key из Redis; при промахе вызывает loader, кэширует
+// результат на ttl под cacheKeyPrefix+key и возвращает его.
+func CachedGet[T any](ctx context.Context, rdb *redis.Client, key string, ttl time.Duration, loader func(ctx context.Context) (T, error)) (T, error) {
+    var value T
+    fullKey := cacheKeyPrefix + key
+
+    if cached, err := rdb.Get(ctx, fullKey).Result(); err == nil {
+        if err := json.Unmarshal([]byte(cached), &value); err == nil {
+            return value, nil
+        }
+    }
+
+    value, err := loader(ctx)
+    if err != nil {
+        return value, err
+    }
+
+    encoded, err := json.Marshal(value)
+    if err != nil {
+        return value, fmt.Errorf("сериализация значения для кэша %s: %w", key, err)
+    }
+
+    if err := rdb.Set(ctx, fullKey, encoded, ttl).Err(); err != nil {
+        log.Printf("⚠️ Не удалось закэшировать %s: %v", fullKey, err)
+    }
+
+    return value, nil
+}
+
+// UserProfile — кэшируемый профиль пользователя из таблицы users
+type UserProfile struct {
+    Username string   `json:"username"`
+    Groups   []string `json:"groups"`
+}
+
+const userProfileTTL = 10 * time.Minute
+
+// GetUserProfile читает профиль пользователя через CachedGet под ключом "users:<username>".
+// Этот же ключ (cacheKeyPrefix + "users:" + username) удаляется триггером
+// cache_invalidate_users (см. EnableCacheInvalidation(db, "users", "username")),
+// поэтому изменение строки в PostgreSQL гарантированно сбрасывает закэшированное значение.
+func GetUserProfile(ctx context.Context, db *sql.DB, rdb *redis.Client, username string) (UserProfile, error) {
+    return CachedGet(ctx, rdb, "users:"+username, userProfileTTL, func(ctx context.Context) (UserProfile, error) {
+        var profile UserProfile
+        var groups pq.StringArray
+        err := db.QueryRowContext(ctx,
+            `SELECT username, groups FROM users WHERE username = $1`, username,
+        ).Scan(&profile.Username, &groups)
+        if err != nil {
+            return profile, fmt.Errorf("чтение профиля пользователя %s: %w", username, err)
+        }
+        profile.Groups = groups
+        return profile, nil
+    })
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "os"
+    "testing"
+    "time"
+
+    _ "github.com/lib/pq"
+    "github.com/redis/go-redis/v9"
+)
+
+// TestCacheInvalidationOnRowUpdate проверяет сквозной путь: мутация строки через одно
+// соединение с PostgreSQL должна привести к удалению соответствующего ключа в Redis.
+// Ключ ("cache:<table>:alice") собран без кавычек JSON-строки, как это делает
+// decodeNotificationID — до его появления триггер удалял бы "cache:<table>:\"alice\"",
+// и этот тест гарантированно падал бы по таймауту, а не молча проходил.
+// Требует настоящих PostgreSQL и Redis (TEST_POSTGRES_DSN/TEST_REDIS_ADDR), поэтому
+// пропускается при их отсутствии — в CI без этих сервисов тест просто не запускается.
+func TestCacheInvalidationOnRowUpdate(t *testing.T) {
+    dsn := os.Getenv("TEST_POSTGRES_DSN")
+    redisAddr := os.Getenv("TEST_REDIS_ADDR")
+    if dsn == "" || redisAddr == "" {
+        t.Skip("TEST_POSTGRES_DSN/TEST_REDIS_ADDR не заданы — пропускаем интеграционный тест")
+    }
+
+    db, err := sql.Open("postgres", dsn)
+    if err != nil {
+        t.Fatalf("подключение к PostgreSQL: %v", err)
+    }
+    defer db.Close()
+
+    const table = "cache_invalidation_test_users"
+    if _, err := db.Exec(`DROP TABLE IF EXISTS ` + table); err != nil {
+        t.Fatalf("удаление тестовой таблицы: %v", err)
+    }
+    if _, err := db.Exec(`CREATE TABLE ` + table + ` (id TEXT PRIMARY KEY, name TEXT NOT NULL)`); err != nil {
+        t.Fatalf("создание тестовой таблицы: %v", err)
+    }
+    defer db.Exec(`DROP TABLE IF EXISTS ` + table)
+
+    if err := EnableCacheInvalidation(db, table, "id"); err != nil {
+        t.Fatalf("EnableCacheInvalidation: %v", err)
+    }
+
+    rdb := redis.NewClient(&redis.Options{Addr: redisAddr})
+    defer rdb.Close()
+
+    ctx := context.Background()
+    key := cacheKeyPrefix + table + ":alice"
+    if err := rdb.Set(ctx, key, "stale", time.Minute).Err(); err != nil {
+        t.Fatalf("запись в Redis: %v", err)
+    }
+
+    go StartCacheListener(dsn, rdb)
+    time.Sleep(500 * time.Millisecond) // дать listener'у подписаться на канал
+
+    if _, err := db.Exec(`INSERT INTO `+table+` (id, name) VALUES ($1, $2)`, "alice", "Alice"); err != nil {
+        t.Fatalf("вставка строки: %v", err)
+    }
+
+    deadline := time.Now().Add(5 * time.Second)
+    for time.Now().Before(deadline) {
+        if _, err := rdb.Get(ctx, key).Result(); err == redis.Nil {
+            return
+        }
+        time.Sleep(100 * time.Millisecond)
+    }
+
+    t.Fatalf("ключ %s не был инвалидирован в течение 5с после изменения строки", key)
+}
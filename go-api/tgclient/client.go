@@ -0,0 +1,122 @@
+// Package tgclient — MTProto-клиент на базе TDLib для режима TELEGRAM_MODE=mtproto.
+// Используется вместо Bot API, когда нужны возможности, недоступные боту:
+// скачивание файлов свыше 20 МБ, отсутствие flood-wait и т. д.
+package tgclient
+
+import (
+    "context"
+    "fmt"
+
+    "ai_seller/config"
+
+    "github.com/zelenin/go-tdlib/client"
+)
+
+// Update — входящее сообщение, приведённое к виду, понятному dialog/jobs конвейеру
+type Update struct {
+    ChatID    int64
+    UserID    int64
+    MessageID int64
+    Text      string
+}
+
+// Client оборачивает TDLib-клиент и публикует входящие сообщения в канал Updates
+type Client struct {
+    td      *client.Client
+    Updates chan Update
+}
+
+// New авторизует TDLib-клиента по TG_API_ID/TG_API_HASH и сохраняет сессию в cfg.TGSessionDir
+func New(cfg *config.Config) (*Client, error) {
+    authorizer := client.ClientAuthorizer()
+    go client.CliInteractor(authorizer)
+
+    authorizer.TdlibParameters <- &client.TdlibParameters{
+        UseTestDc:           false,
+        DatabaseDirectory:   cfg.TGSessionDir + "/database",
+        FilesDirectory:      cfg.TGSessionDir + "/files",
+        UseFileDatabase:     true,
+        UseChatInfoDatabase: true,
+        UseMessageDatabase:  true,
+        UseSecretChats:      false,
+        ApiId:               int32(cfg.TGAPIID),
+        ApiHash:             cfg.TGAPIHash,
+        SystemLanguageCode:  "ru",
+        DeviceModel:         "ai_seller",
+        SystemVersion:       "1.0",
+        ApplicationVersion:  "1.0",
+    }
+
+    td, err := client.NewClient(authorizer)
+    if err != nil {
+        return nil, fmt.Errorf("запуск TDLib-клиента: %w", err)
+    }
+
+    return &Client{
+        td:      td,
+        Updates: make(chan Update, 100),
+    }, nil
+}
+
+// Listen читает обновления TDLib и перекладывает текстовые сообщения в Updates.
+// Блокируется до отмены ctx или закрытия внутреннего слушателя TDLib.
+func (c *Client) Listen(ctx context.Context) {
+    listener := c.td.GetListener()
+    defer listener.Close()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case raw, ok := <-listener.Updates:
+            if !ok {
+                return
+            }
+            c.handleUpdate(raw)
+        }
+    }
+}
+
+func (c *Client) handleUpdate(raw client.Type) {
+    update, ok := raw.(*client.UpdateNewMessage)
+    if !ok {
+        return
+    }
+
+    content, ok := update.Message.Content.(*client.MessageText)
+    if !ok {
+        return
+    }
+
+    sender, ok := update.Message.SenderId.(*client.MessageSenderUser)
+    if !ok {
+        return
+    }
+
+    c.Updates <- Update{
+        ChatID:    update.Message.ChatId,
+        UserID:    sender.UserId,
+        MessageID: int64(update.Message.Id),
+        Text:      content.Text.Text,
+    }
+}
+
+// SendMessage отправляет текстовое сообщение в чат chatID через TDLib
+func (c *Client) SendMessage(chatID int64, text string) error {
+    _, err := c.td.SendMessage(&client.SendMessageRequest{
+        ChatId: chatID,
+        InputMessageContent: &client.InputMessageText{
+            Text: &client.FormattedText{Text: text},
+        },
+    })
+    if err != nil {
+        return fmt.Errorf("отправка сообщения через TDLib: %w", err)
+    }
+    return nil
+}
+
+// Close завершает работу TDLib-клиента
+func (c *Client) Close() error {
+    _, err := c.td.Close()
+    return err
+}